@@ -2,6 +2,9 @@ package main
 
 import (
 	"bufio"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"io"
 	"maps"
@@ -38,7 +41,11 @@ const layoutExamples = `  ANSIC       "Mon Jan _2 15:04:05 2006"
   Unix-Milli  "1136239445000"
   Unix-Micro  "1136239445000000"
 
-  Arbitrary formats are also supported. See https://pkg.go.dev/time as a reference.`
+  Arbitrary Go layouts are also supported. See https://pkg.go.dev/time as a reference.
+
+  strftime-style formats are recognized by the presence of "%" directives,
+  e.g. "%Y-%m-%dT%H:%M:%S" or "%b %e %H:%M:%S". Supported directives:
+  %Y %y %m %d %e %H %I %M %S %p %b %h %B %a %A %z %:z %Z %j %s %f %N %%`
 
 var knownLayouts = map[string]string{
 	"ansic":       time.ANSIC,
@@ -75,7 +82,7 @@ type guessRule struct {
 var guessRules = []guessRule{
 	{regexp.MustCompile(`^\d{10,19}(?:\.\d+)?`), []string{"unix", "unix-milli", "unix-micro"}},
 	{regexp.MustCompile(`^\d{4}`), []string{"rfc3339", "rfc3339nano", "datetime", "dateonly"}},
-	{regexp.MustCompile(`[A-Za-z]{3,4}|[+-]\d{4}`), []string{"unixdate", "rubydate", "rfc822", "rfc822z", "rfc850", "rfc1123", "rfc1123z", "rfc3339", "rfc3339nano"}},
+	{regexp.MustCompile(`[A-Za-z]{3,4}|[+-]\d{4}`), []string{"unixdate", "rubydate", "rfc822", "rfc822z", "rfc850", "rfc1123", "rfc1123z", "rfc3339", "rfc3339nano", "%d/%b/%Y:%H:%M:%S %z"}},
 	{regexp.MustCompile(`^[A-Za-z]{3},?`), []string{"ansic", "unixdate", "rubydate", "rfc822", "rfc822z", "rfc850", "rfc1123", "rfc1123z", "stamp", "stampmilli", "stampmicro", "stampnano"}},
 	{regexp.MustCompile(`\d{2}:\d{2}:\d{2}`), []string{"datetime", "timeonly", "ansic", "unixdate", "rubydate", "rfc850", "rfc1123", "rfc1123z"}},
 	{regexp.MustCompile(`\d{1,2}:\d{2}(AM|PM)`), []string{"kitchen"}},
@@ -107,11 +114,15 @@ const (
 )
 
 type options struct {
-	format   string
-	interval time.Duration
-	barlen   int
-	location locationValue
-	color    string
+	format      string
+	interval    time.Duration
+	barlen      int
+	location    locationValue
+	color       string
+	value       string
+	agg         string
+	output      string
+	outputShape string
 }
 
 type locationValue struct {
@@ -139,11 +150,15 @@ func parseFlags() (*options, error) {
 	var opts options
 	opts.location.Location = time.Local
 
-	pflag.StringVarP(&opts.format, "format", "f", "", "Input time format (default: auto)")
+	pflag.StringVarP(&opts.format, "format", "f", "", "Input time format: a named/Go layout or a strftime-style format (default: auto)")
 	pflag.DurationVarP(&opts.interval, "interval", "i", 5*time.Minute, "Bin width as duration (e.g. 30s, 1m, 1h)")
 	pflag.IntVarP(&opts.barlen, "barlength", "b", 120, "Length of the longest bar")
 	pflag.VarP(&opts.location, "location", "l", "Timezone location (e.g., UTC, Asia/Tokyo)")
 	pflag.StringVar(&opts.color, "color", "auto", "Markup bar color [never|always|auto]")
+	pflag.StringVar(&opts.value, "value", "", "Numeric value selector: field:N, regex:PATTERN, or key:NAME (default: count lines)")
+	pflag.StringVar(&opts.agg, "agg", "count", "Aggregation applied to --value: "+strings.Join(aggNames, "|"))
+	pflag.StringVar(&opts.output, "output", "text", "Output format [text|json|csv|tsv]")
+	pflag.StringVar(&opts.outputShape, "output-shape", "long", "Row shape for csv/tsv output [long|wide]")
 
 	pflag.CommandLine.SortFlags = false
 	pflag.Usage = func() {
@@ -228,9 +243,102 @@ func stringToTime(s, format string) (time.Time, error) {
 		return time.Parse(layout, s)
 	}
 
+	if strings.Contains(format, "%") {
+		if format == "%s" || strings.HasPrefix(format, "%s.") {
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("failed to parse epoch time: %s", s)
+			}
+			return time.UnixMicro(int64(v * float64(epochLayouts["unix"]))), nil
+		}
+
+		layout, err := strftimeToGoLayout(format)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Parse(layout, s)
+	}
+
 	return time.Parse(format, s)
 }
 
+// strftimeToGoLayout translates a strftime-style format string (e.g.
+// "%Y-%m-%dT%H:%M:%S") into the equivalent Go reference-time layout. The
+// "%s" epoch directive is not translatable to a Go layout and must be
+// handled by the caller before reaching here.
+func strftimeToGoLayout(format string) (string, error) {
+	var layout strings.Builder
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			layout.WriteByte(format[i])
+			continue
+		}
+
+		pos := i
+		i++
+		if i >= len(format) {
+			return "", fmt.Errorf("strftime format %q: dangling %% at position %d", format, pos)
+		}
+
+		if format[i] == ':' {
+			if i+1 < len(format) && format[i+1] == 'z' {
+				layout.WriteString("-07:00")
+				i++
+				continue
+			}
+			return "", fmt.Errorf("strftime format %q: unknown directive %%%c at position %d", format, format[i], pos)
+		}
+
+		switch format[i] {
+		case 'Y':
+			layout.WriteString("2006")
+		case 'y':
+			layout.WriteString("06")
+		case 'm':
+			layout.WriteString("01")
+		case 'd':
+			layout.WriteString("02")
+		case 'e':
+			layout.WriteString("_2")
+		case 'H':
+			layout.WriteString("15")
+		case 'I':
+			layout.WriteString("03")
+		case 'M':
+			layout.WriteString("04")
+		case 'S':
+			layout.WriteString("05")
+		case 'p':
+			layout.WriteString("PM")
+		case 'b', 'h':
+			layout.WriteString("Jan")
+		case 'B':
+			layout.WriteString("January")
+		case 'a':
+			layout.WriteString("Mon")
+		case 'A':
+			layout.WriteString("Monday")
+		case 'z':
+			layout.WriteString("-0700")
+		case 'Z':
+			layout.WriteString("MST")
+		case 'j':
+			layout.WriteString("002")
+		case 'f':
+			layout.WriteString("000000")
+		case 'N':
+			layout.WriteString("999999999")
+		case '%':
+			layout.WriteByte('%')
+		default:
+			return "", fmt.Errorf("strftime format %q: unknown directive %%%c at position %d", format, format[i], pos)
+		}
+	}
+
+	return layout.String(), nil
+}
+
 func guessTime(s string) (time.Time, error) {
 	for _, rule := range guessRules {
 		if rule.re.MatchString(s) {
@@ -260,25 +368,355 @@ func parseLeadingTime(s, format string) (time.Time, string) {
 	return time.Time{}, s
 }
 
+// aggregator accumulates numeric samples for a single (bin, series) and
+// reduces them to one bar magnitude.
+type aggregator interface {
+	add(v float64)
+	// merge folds another aggregator of the same concrete type into this
+	// one, combining their underlying state rather than their reduced
+	// value() (e.g. two countAggs merge by summing n, not by counting the
+	// merge as a single new sample).
+	merge(other aggregator)
+	value() float64
+}
+
+type countAgg struct{ n int }
+
+func (a *countAgg) add(float64) { a.n++ }
+func (a *countAgg) merge(other aggregator) {
+	a.n += other.(*countAgg).n
+}
+func (a *countAgg) value() float64 { return float64(a.n) }
+
+type sumAgg struct{ sum float64 }
+
+func (a *sumAgg) add(v float64) { a.sum += v }
+func (a *sumAgg) merge(other aggregator) {
+	a.sum += other.(*sumAgg).sum
+}
+func (a *sumAgg) value() float64 { return a.sum }
+
+type meanAgg struct {
+	sum float64
+	n   int
+}
+
+func (a *meanAgg) add(v float64) {
+	a.sum += v
+	a.n++
+}
+
+func (a *meanAgg) merge(other aggregator) {
+	o := other.(*meanAgg)
+	a.sum += o.sum
+	a.n += o.n
+}
+
+func (a *meanAgg) value() float64 {
+	if a.n == 0 {
+		return 0
+	}
+	return a.sum / float64(a.n)
+}
+
+type maxAgg struct {
+	v   float64
+	set bool
+}
+
+func (a *maxAgg) add(v float64) {
+	if !a.set || v > a.v {
+		a.v, a.set = v, true
+	}
+}
+
+func (a *maxAgg) merge(other aggregator) {
+	o := other.(*maxAgg)
+	if o.set {
+		a.add(o.v)
+	}
+}
+
+func (a *maxAgg) value() float64 { return a.v }
+
+type minAgg struct {
+	v   float64
+	set bool
+}
+
+func (a *minAgg) add(v float64) {
+	if !a.set || v < a.v {
+		a.v, a.set = v, true
+	}
+}
+
+func (a *minAgg) merge(other aggregator) {
+	o := other.(*minAgg)
+	if o.set {
+		a.add(o.v)
+	}
+}
+
+func (a *minAgg) value() float64 { return a.v }
+
+// quantileAgg keeps every sample and computes an exact quantile on demand.
+type quantileAgg struct {
+	p       float64
+	samples []float64
+}
+
+func (a *quantileAgg) add(v float64) {
+	a.samples = append(a.samples, v)
+}
+
+func (a *quantileAgg) merge(other aggregator) {
+	a.samples = append(a.samples, other.(*quantileAgg).samples...)
+}
+
+func (a *quantileAgg) value() float64 {
+	if len(a.samples) == 0 {
+		return 0
+	}
+
+	sorted := slices.Clone(a.samples)
+	slices.Sort(sorted)
+
+	idx := int(a.p * float64(len(sorted)-1))
+	return sorted[idx]
+}
+
+var aggNames = []string{"count", "sum", "avg", "max", "min", "p50", "p90", "p99"}
+
+// newAggregatorFunc returns a constructor for fresh aggregators of the given
+// kind, so that each (bin, series) pair gets its own accumulator.
+func newAggregatorFunc(agg string) (func() aggregator, error) {
+	switch agg {
+	case "", "count":
+		return func() aggregator { return &countAgg{} }, nil
+	case "sum":
+		return func() aggregator { return &sumAgg{} }, nil
+	case "avg":
+		return func() aggregator { return &meanAgg{} }, nil
+	case "max":
+		return func() aggregator { return &maxAgg{} }, nil
+	case "min":
+		return func() aggregator { return &minAgg{} }, nil
+	case "p50":
+		return func() aggregator { return &quantileAgg{p: 0.50} }, nil
+	case "p90":
+		return func() aggregator { return &quantileAgg{p: 0.90} }, nil
+	case "p99":
+		return func() aggregator { return &quantileAgg{p: 0.99} }, nil
+	default:
+		return nil, fmt.Errorf("invalid agg \"%s\" (want one of: %s)", agg, strings.Join(aggNames, "|"))
+	}
+}
+
+type valueSelectorKind int
+
+const (
+	valueSelectorCount valueSelectorKind = iota
+	valueSelectorField
+	valueSelectorRegex
+	valueSelectorKey
+)
+
+// valueSelector extracts the numeric sample to feed an aggregator from the
+// portion of a line that follows the timestamp. With no --value flag it
+// falls back to counting lines, matching the pre-existing behavior.
+type valueSelector struct {
+	kind  valueSelectorKind
+	field int
+	re    *regexp.Regexp
+	key   string
+}
+
+func parseValueSelector(spec string) (*valueSelector, error) {
+	if spec == "" {
+		return &valueSelector{kind: valueSelectorCount}, nil
+	}
+
+	kind, arg, ok := strings.Cut(spec, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid value selector \"%s\": expected kind:arg", spec)
+	}
+
+	switch kind {
+	case "field":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid field selector \"%s\": expected a positive field number", spec)
+		}
+		return &valueSelector{kind: valueSelectorField, field: n}, nil
+	case "regex":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex selector \"%s\": %w", spec, err)
+		}
+		if re.NumSubexp() < 1 {
+			return nil, fmt.Errorf("invalid regex selector \"%s\": pattern must contain a capture group", spec)
+		}
+		return &valueSelector{kind: valueSelectorRegex, re: re}, nil
+	case "key":
+		if arg == "" {
+			return nil, fmt.Errorf("invalid key selector \"%s\": expected a key name", spec)
+		}
+		return &valueSelector{kind: valueSelectorKey, key: arg}, nil
+	default:
+		return nil, fmt.Errorf("invalid value selector \"%s\": unknown kind \"%s\"", spec, kind)
+	}
+}
+
+// parseValue extracts the numeric sample from remainder (the part of the
+// line left after the timestamp) and returns it alongside the series
+// label: remainder with the consumed numeric token stripped out, so that
+// lines differing only in that value still group into the same series
+// instead of each becoming its own single-sample series. Lines with no
+// categorical text left over collapse to the empty-string series, the
+// same default series used when --value is unset.
+func (vs *valueSelector) parseValue(remainder string) (float64, string, error) {
+	switch vs.kind {
+	case valueSelectorCount:
+		return 1, remainder, nil
+	case valueSelectorField:
+		fields := strings.Fields(remainder)
+		if vs.field > len(fields) {
+			return 0, remainder, fmt.Errorf("field %d not found in line: %q", vs.field, remainder)
+		}
+		idx := vs.field - 1
+		v, err := strconv.ParseFloat(fields[idx], 64)
+		if err != nil {
+			return 0, remainder, fmt.Errorf("field %d is not numeric: %q", vs.field, fields[idx])
+		}
+		label := strings.Join(slices.Delete(slices.Clone(fields), idx, idx+1), " ")
+		return v, label, nil
+	case valueSelectorRegex:
+		m := vs.re.FindStringSubmatchIndex(remainder)
+		if m == nil || m[2] == -1 {
+			return 0, remainder, fmt.Errorf("regex did not match line: %q", remainder)
+		}
+		raw := remainder[m[2]:m[3]]
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return 0, remainder, fmt.Errorf("regex capture is not numeric: %q", raw)
+		}
+		label := collapseSpaces(remainder[:m[0]] + remainder[m[1]:])
+		return v, label, nil
+	case valueSelectorKey:
+		v, label, err := extractKeyValue(remainder, vs.key)
+		if err != nil {
+			return 0, remainder, err
+		}
+		return v, label, nil
+	default:
+		return 0, remainder, fmt.Errorf("unknown value selector kind")
+	}
+}
+
+// collapseSpaces normalizes the whitespace left behind after a token is
+// cut out of a line, so the series label doesn't carry a run of spaces
+// where the value used to be.
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// extractKeyValue looks up key in remainder, treating remainder as a JSON
+// object when it looks like one and as logfmt (key=value) otherwise. It
+// returns the numeric value and remainder with the key's field removed,
+// so lines sharing every other field group into the same series.
+func extractKeyValue(remainder, key string) (float64, string, error) {
+	if strings.HasPrefix(strings.TrimSpace(remainder), "{") {
+		var obj map[string]any
+		if err := json.Unmarshal([]byte(remainder), &obj); err == nil {
+			v, ok := obj[key]
+			if !ok {
+				return 0, remainder, fmt.Errorf("key %q not found in line: %q", key, remainder)
+			}
+
+			f, ok := toFloat(v)
+			if !ok {
+				return 0, remainder, fmt.Errorf("key %q is not numeric: %v", key, v)
+			}
+
+			delete(obj, key)
+			if len(obj) == 0 {
+				return f, "", nil
+			}
+
+			label, err := json.Marshal(obj)
+			if err != nil {
+				return 0, remainder, fmt.Errorf("failed to re-encode line without key %q: %w", key, err)
+			}
+			return f, string(label), nil
+		}
+	}
+
+	re := regexp.MustCompile(`(?:^|\s)` + regexp.QuoteMeta(key) + `="([^"]*)"|(?:^|\s)` + regexp.QuoteMeta(key) + `=(\S+)`)
+	m := re.FindStringSubmatchIndex(remainder)
+	if m == nil {
+		return 0, remainder, fmt.Errorf("key %q not found in line: %q", key, remainder)
+	}
+
+	var raw string
+	if m[2] != -1 {
+		raw = remainder[m[2]:m[3]]
+	} else {
+		raw = remainder[m[4]:m[5]]
+	}
+
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, remainder, fmt.Errorf("key %q is not numeric: %q", key, raw)
+	}
+
+	label := collapseSpaces(remainder[:m[0]] + remainder[m[1]:])
+	return f, label, nil
+}
+
+// toFloat converts a decoded JSON value to a float64, accepting both
+// numeric and numeric-looking string values.
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// formatValue renders an aggregate magnitude, printing whole numbers (as
+// produced by the count aggregator) without a trailing decimal point.
+func formatValue(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimRight(s, ".")
+	return s
+}
+
 type bins struct {
 	base    time.Time
 	size    time.Duration
 	total   int
-	counts  []map[string]int
+	newAgg  func() aggregator
+	values  []map[string]aggregator
 	series  map[string]struct{}
 	minTime time.Time
 	maxTime time.Time
 }
 
-func newBins(size time.Duration) *bins {
+func newBins(size time.Duration, newAgg func() aggregator) *bins {
 	return &bins{
 		size:   size,
-		counts: []map[string]int{},
+		newAgg: newAgg,
+		values: []map[string]aggregator{},
 		series: make(map[string]struct{}),
 	}
 }
 
-func (b *bins) add(t time.Time, seriesName string) {
+func (b *bins) add(t time.Time, seriesName string, value float64) {
 	if b.minTime.IsZero() || t.Before(b.minTime) {
 		b.minTime = t
 	}
@@ -297,20 +735,323 @@ func (b *bins) add(t time.Time, seriesName string) {
 	switch {
 	case idx < 0:
 		grow := -idx
-		newCounts := make([]map[string]int, grow)
-		b.counts = append(newCounts, b.counts...)
+		newValues := make([]map[string]aggregator, grow)
+		b.values = append(newValues, b.values...)
 		b.base = b.base.Add(-time.Duration(grow) * b.size)
-		b.counts[0] = map[string]int{seriesName: 1}
-	case idx >= len(b.counts):
-		grow := idx - len(b.counts) + 1
-		b.counts = append(b.counts, make([]map[string]int, grow)...)
-		b.counts[idx] = map[string]int{seriesName: 1}
+		b.values[0] = map[string]aggregator{seriesName: b.newAgg()}
+		b.values[0][seriesName].add(value)
+	case idx >= len(b.values):
+		grow := idx - len(b.values) + 1
+		b.values = append(b.values, make([]map[string]aggregator, grow)...)
+		b.values[idx] = map[string]aggregator{seriesName: b.newAgg()}
+		b.values[idx][seriesName].add(value)
+	default:
+		if b.values[idx] == nil {
+			b.values[idx] = make(map[string]aggregator)
+		}
+		agg, ok := b.values[idx][seriesName]
+		if !ok {
+			agg = b.newAgg()
+			b.values[idx][seriesName] = agg
+		}
+		agg.add(value)
+	}
+}
+
+// collapseOtherSeries keeps the top (limit-2) series by total value and
+// merges the rest into a single otherSeriesName series per bin, mutating
+// b in place, so limit-1 series remain. It returns the resulting sorted
+// series names, with otherSeriesName last. Merging uses aggregator.merge
+// rather than re-feeding agg.value() through add, since for count, avg
+// and the quantile aggregators the two are not equivalent (e.g. add on a
+// countAgg always increments n by one, regardless of the value passed in).
+func (b *bins) collapseOtherSeries(limit int, newAgg func() aggregator) []string {
+	seriesTotals := make(map[string]float64)
+	for _, binValues := range b.values {
+		for seriesName, agg := range binValues {
+			seriesTotals[seriesName] += agg.value()
+		}
+	}
+
+	allSeriesNames := slices.Collect(maps.Keys(b.series))
+	slices.SortFunc(allSeriesNames, func(a, c string) int {
+		if seriesTotals[c] != seriesTotals[a] {
+			return cmp.Compare(seriesTotals[c], seriesTotals[a])
+		}
+		return strings.Compare(a, c)
+	})
+
+	topSeries := allSeriesNames[:limit-2]
+	otherSeriesSet := make(map[string]struct{})
+	for _, s := range allSeriesNames[limit-2:] {
+		otherSeriesSet[s] = struct{}{}
+	}
+
+	newValues := make([]map[string]aggregator, len(b.values))
+	for i, binValues := range b.values {
+		newBinValues := make(map[string]aggregator)
+		for seriesName, agg := range binValues {
+			if _, isOther := otherSeriesSet[seriesName]; isOther {
+				otherAgg, ok := newBinValues[otherSeriesName]
+				if !ok {
+					otherAgg = newAgg()
+					newBinValues[otherSeriesName] = otherAgg
+				}
+				otherAgg.merge(agg)
+			} else {
+				newBinValues[seriesName] = agg
+			}
+		}
+		newValues[i] = newBinValues
+	}
+	b.values = newValues
+
+	slices.Sort(topSeries)
+	seriesNames := append(topSeries, otherSeriesName)
+
+	b.series = make(map[string]struct{})
+	for _, name := range seriesNames {
+		b.series[name] = struct{}{}
+	}
+
+	return seriesNames
+}
+
+// binRecord is a rendering-ready snapshot of one bin: each series'
+// aggregator reduced to its final magnitude.
+type binRecord struct {
+	start  time.Time
+	end    time.Time
+	total  float64
+	series map[string]float64
+}
+
+// histogram is the renderer-facing view of a *bins: every aggregator
+// resolved to a float64 so renderers don't need to know about aggregation.
+type histogram struct {
+	totalCount  int
+	minTime     time.Time
+	maxTime     time.Time
+	interval    time.Duration
+	agg         string
+	seriesNames []string
+	bins        []binRecord
+}
+
+func newHistogram(b *bins, seriesNames []string, agg string) *histogram {
+	bins := make([]binRecord, len(b.values))
+	for i, seriesValues := range b.values {
+		start := b.base.Add(time.Duration(i) * b.size)
+		rec := binRecord{
+			start:  start,
+			end:    start.Add(b.size),
+			series: make(map[string]float64, len(seriesValues)),
+		}
+		for name, agg := range seriesValues {
+			v := agg.value()
+			rec.series[name] = v
+			rec.total += v
+		}
+		bins[i] = rec
+	}
+
+	return &histogram{
+		totalCount:  b.total,
+		minTime:     b.minTime,
+		maxTime:     b.maxTime,
+		interval:    b.size,
+		agg:         agg,
+		seriesNames: seriesNames,
+		bins:        bins,
+	}
+}
+
+// renderer turns a *histogram into output on w.
+type renderer interface {
+	render(w io.Writer, h *histogram) error
+}
+
+func newRenderer(output, shape string, barlen int, styleFunc func(string, int) string) (renderer, error) {
+	switch output {
+	case "", "text":
+		return &textRenderer{barlen: barlen, styleFunc: styleFunc}, nil
+	case "json":
+		return &jsonRenderer{}, nil
+	case "csv":
+		return &csvRenderer{comma: ',', shape: shape}, nil
+	case "tsv":
+		return &csvRenderer{comma: '\t', shape: shape}, nil
 	default:
-		if b.counts[idx] == nil {
-			b.counts[idx] = make(map[string]int)
+		return nil, fmt.Errorf("invalid output \"%s\"", output)
+	}
+}
+
+type textRenderer struct {
+	barlen    int
+	styleFunc func(string, int) string
+}
+
+func (r *textRenderer) render(w io.Writer, h *histogram) error {
+	if h.totalCount == 0 {
+		fmt.Fprintln(w, "Total count = 0")
+		return nil
+	}
+
+	fmt.Fprintf(w, "Total count: %d\n", h.totalCount)
+	fmt.Fprintf(w, "Time range:  %s - %s\n", h.minTime.Format(time.RFC3339), h.maxTime.Format(time.RFC3339))
+	fmt.Fprintf(w, "Aggregation: %s\n", h.agg)
+	if len(h.seriesNames) != 1 || h.seriesNames[0] != "" {
+		fmt.Fprintln(w, "Legend:")
+		for _, name := range h.seriesNames {
+			fmt.Fprintf(w, "    %s = %s\n", r.styleFunc(name, 1), name)
+		}
+	}
+	fmt.Fprintln(w)
+
+	maxTotalInBin := 0.0
+	for _, rec := range h.bins {
+		if rec.total > maxTotalInBin {
+			maxTotalInBin = rec.total
 		}
-		b.counts[idx][seriesName]++
 	}
+	if maxTotalInBin == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', tabwriter.AlignRight)
+	for _, rec := range h.bins {
+		if len(rec.series) == 0 || rec.total == 0 {
+			fmt.Fprintf(tw, "[\t%s\t]\t%6s\t  %s\n", rec.start.Format(time.RFC3339), formatValue(0), "")
+			continue
+		}
+
+		barLen := int(float64(r.barlen) * rec.total / maxTotalInBin)
+		barLens := make(map[string]int)
+
+		assignedBarLen := 0
+		fractionBarLens := make(map[string]int)
+
+		for _, name := range h.seriesNames {
+			if v, ok := rec.series[name]; ok {
+				val := int(v / rec.total * float64(barLen) * 100)
+				barLens[name] = val / 100
+				fractionBarLens[name] = val % 100
+				assignedBarLen += barLens[name]
+			}
+		}
+
+		fractionSeriesNames := slices.Collect(maps.Keys(fractionBarLens))
+		slices.SortStableFunc(fractionSeriesNames, func(a, b string) int {
+			return fractionBarLens[b] - fractionBarLens[a]
+		})
+		for i := range barLen - assignedBarLen {
+			seriesToIncrement := fractionSeriesNames[i%len(fractionSeriesNames)]
+			barLens[seriesToIncrement]++
+		}
+
+		var barBuilder strings.Builder
+		for _, name := range h.seriesNames {
+			if barPartLen, ok := barLens[name]; ok && barPartLen > 0 {
+				barBuilder.WriteString(r.styleFunc(name, barPartLen))
+			}
+		}
+
+		fmt.Fprintf(tw, "[\t%s\t]\t%6s\t  %s\n", rec.start.Format(time.RFC3339), formatValue(rec.total), barBuilder.String())
+	}
+
+	return tw.Flush()
+}
+
+type jsonBinOutput struct {
+	Start  string             `json:"start"`
+	End    string             `json:"end"`
+	Total  float64            `json:"total"`
+	Series map[string]float64 `json:"series"`
+}
+
+type jsonOutput struct {
+	MinTime     string          `json:"min_time"`
+	MaxTime     string          `json:"max_time"`
+	Interval    string          `json:"interval"`
+	Aggregation string          `json:"aggregation"`
+	Legend      []string        `json:"legend"`
+	Bins        []jsonBinOutput `json:"bins"`
+}
+
+type jsonRenderer struct{}
+
+func (r *jsonRenderer) render(w io.Writer, h *histogram) error {
+	out := jsonOutput{
+		MinTime:     h.minTime.Format(time.RFC3339),
+		MaxTime:     h.maxTime.Format(time.RFC3339),
+		Interval:    h.interval.String(),
+		Aggregation: h.agg,
+		Legend:      h.seriesNames,
+		Bins:        make([]jsonBinOutput, len(h.bins)),
+	}
+
+	for i, rec := range h.bins {
+		out.Bins[i] = jsonBinOutput{
+			Start:  rec.start.Format(time.RFC3339),
+			End:    rec.end.Format(time.RFC3339),
+			Total:  rec.total,
+			Series: rec.series,
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// csvRenderer emits CSV or TSV rows (selected via comma), either one row
+// per (bin, series) present ("long" shape) or one row per bin with a
+// column per series ("wide" shape).
+type csvRenderer struct {
+	comma rune
+	shape string
+}
+
+func (r *csvRenderer) render(w io.Writer, h *histogram) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = r.comma
+
+	switch r.shape {
+	case "wide":
+		if err := cw.Write(append([]string{"timestamp"}, h.seriesNames...)); err != nil {
+			return err
+		}
+		for _, rec := range h.bins {
+			row := make([]string, len(h.seriesNames)+1)
+			row[0] = rec.start.Format(time.RFC3339)
+			for i, name := range h.seriesNames {
+				row[i+1] = formatValue(rec.series[name])
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	case "", "long":
+		if err := cw.Write([]string{"timestamp", "series", "count"}); err != nil {
+			return err
+		}
+		for _, rec := range h.bins {
+			for _, name := range h.seriesNames {
+				v, ok := rec.series[name]
+				if !ok {
+					continue
+				}
+				if err := cw.Write([]string{rec.start.Format(time.RFC3339), name, formatValue(v)}); err != nil {
+					return err
+				}
+			}
+		}
+	default:
+		return fmt.Errorf("invalid output-shape \"%s\"", r.shape)
+	}
+
+	cw.Flush()
+	return cw.Error()
 }
 
 func run() error {
@@ -327,32 +1068,42 @@ func run() error {
 		defer c.Close()
 	}
 
-	b := newBins(opts.interval)
+	vs, err := parseValueSelector(opts.value)
+	if err != nil {
+		return err
+	}
+
+	newAgg, err := newAggregatorFunc(opts.agg)
+	if err != nil {
+		return err
+	}
+
+	b := newBins(opts.interval, newAgg)
 	scanner := bufio.NewScanner(reader)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 
-		t, seriesName := parseLeadingTime(line, opts.format)
+		t, remainder := parseLeadingTime(line, opts.format)
 		if t.IsZero() {
 			continue
 		}
 
+		value, seriesName, err := vs.parseValue(remainder)
+		if err != nil {
+			continue
+		}
+
 		t = t.In(opts.location.Location)
 		if t.Year() == 0 {
 			t = t.AddDate(time.Now().Year(), 0, 0)
 		}
 
-		b.add(t, seriesName)
+		b.add(t, seriesName, value)
 	}
 	if err := scanner.Err(); err != nil {
 		return err
 	}
 
-	if b.total == 0 {
-		fmt.Println("Total count = 0")
-		return nil
-	}
-
 	var style barStyleOption
 	switch opts.color {
 	case "always":
@@ -369,52 +1120,11 @@ func run() error {
 		return fmt.Errorf("invalid color \"%s\"", opts.color)
 	}
 
-	var seriesNames []string
-	var seriesLimit = len(barStyles) + 1
+	seriesLimit := len(barStyles) + 1
 
+	var seriesNames []string
 	if len(b.series) > seriesLimit {
-		seriesTotals := make(map[string]int)
-		for _, binCounts := range b.counts {
-			for seriesName, count := range binCounts {
-				seriesTotals[seriesName] += count
-			}
-		}
-
-		allSeriesNames := slices.Collect(maps.Keys(b.series))
-		slices.SortFunc(allSeriesNames, func(a, b string) int {
-			if seriesTotals[b] != seriesTotals[a] {
-				return seriesTotals[b] - seriesTotals[a]
-			}
-			return strings.Compare(a, b)
-		})
-
-		topSeries := allSeriesNames[:seriesLimit-2]
-		otherSeriesSet := make(map[string]struct{})
-		for _, s := range allSeriesNames[seriesLimit-2:] {
-			otherSeriesSet[s] = struct{}{}
-		}
-
-		newCounts := make([]map[string]int, len(b.counts))
-		for i, binCounts := range b.counts {
-			newBinCounts := make(map[string]int)
-			for seriesName, count := range binCounts {
-				if _, isOther := otherSeriesSet[seriesName]; isOther {
-					newBinCounts[otherSeriesName] += count
-				} else {
-					newBinCounts[seriesName] = count
-				}
-			}
-			newCounts[i] = newBinCounts
-		}
-		b.counts = newCounts
-
-		slices.Sort(topSeries)
-		seriesNames = append(topSeries, otherSeriesName)
-
-		b.series = make(map[string]struct{})
-		for _, name := range seriesNames {
-			b.series[name] = struct{}{}
-		}
+		seriesNames = b.collapseOtherSeries(seriesLimit, newAgg)
 	} else {
 		seriesNames = slices.Collect(maps.Keys(b.series))
 		slices.Sort(seriesNames)
@@ -437,79 +1147,18 @@ func run() error {
 		}
 	}
 
-	fmt.Printf("Total count: %d\n", b.total)
-	fmt.Printf("Time range:  %s - %s\n", b.minTime.Format(time.RFC3339), b.maxTime.Format(time.RFC3339))
-	if len(seriesNames) != 1 || seriesNames[0] != "" {
-		fmt.Println("Legend:")
-		for _, name := range seriesNames {
-			fmt.Printf("    %s = %s\n", styleFunc(name, 1), name)
-		}
-	}
-	fmt.Println()
-
-	maxTotalInBin := 0
-	for _, seriesCounts := range b.counts {
-		currentTotal := 0
-		for _, count := range seriesCounts {
-			currentTotal += count
-		}
-		if currentTotal > maxTotalInBin {
-			maxTotalInBin = currentTotal
-		}
+	aggName := opts.agg
+	if aggName == "" {
+		aggName = "count"
 	}
-	if maxTotalInBin == 0 {
-		return nil
-	}
-
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
-	for i, seriesCounts := range b.counts {
-		t := b.base.Add(time.Duration(i) * b.size)
-
-		totalInBin := 0
-		for _, count := range seriesCounts {
-			totalInBin += count
-		}
-		if totalInBin == 0 {
-			fmt.Fprintf(w, "[\t%s\t]\t%6d\t  %s\n", t.Format(time.RFC3339), 0, "")
-			continue
-		}
-
-		barLen := opts.barlen * totalInBin / maxTotalInBin
-		barLens := make(map[string]int)
-
-		assignedBarLen := 0
-		fractionBarLens := make(map[string]int)
 
-		for _, seriesName := range seriesNames {
-			if count, ok := seriesCounts[seriesName]; ok {
-				val := (count * barLen * 100) / totalInBin
-				barLens[seriesName] = val / 100
-				fractionBarLens[seriesName] = val % 100
-				assignedBarLen += barLens[seriesName]
-			}
-		}
-
-		fractionSeriesNames := slices.Collect(maps.Keys(fractionBarLens))
-		slices.SortStableFunc(fractionSeriesNames, func(a, b string) int {
-			return fractionBarLens[b] - fractionBarLens[a]
-		})
-		for i := range barLen - assignedBarLen {
-			seriesToIncrement := fractionSeriesNames[i%len(fractionSeriesNames)]
-			barLens[seriesToIncrement]++
-		}
-
-		var barBuilder strings.Builder
-		for _, seriesName := range seriesNames {
-			if barPartLen, ok := barLens[seriesName]; ok && barPartLen > 0 {
-				barBuilder.WriteString(styleFunc(seriesName, barPartLen))
-			}
-		}
-
-		fmt.Fprintf(w, "[\t%s\t]\t%6d\t  %s\n", t.Format(time.RFC3339), totalInBin, barBuilder.String())
+	rdr, err := newRenderer(opts.output, opts.outputShape, opts.barlen, styleFunc)
+	if err != nil {
+		return err
 	}
-	w.Flush()
 
-	return nil
+	h := newHistogram(b, seriesNames, aggName)
+	return rdr.render(os.Stdout, h)
 }
 
 func main() {
@@ -1,6 +1,10 @@
 package main
 
 import (
+	"bytes"
+	"os"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -53,3 +57,415 @@ func TestStringToTimeAutoDetectRFC3339(t *testing.T) {
 		t.Fatalf("unexpected time\nexpected: %v\n     got: %v", expected, got)
 	}
 }
+
+func TestStrftimeToGoLayout(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		expected string
+	}{
+		{"iso8601", "%Y-%m-%dT%H:%M:%S", "2006-01-02T15:04:05"},
+		{"syslog", "%b %e %H:%M:%S", "Jan _2 15:04:05"},
+		{"12-hour", "%I:%M:%S %p", "03:04:05 PM"},
+		{"numeric zone", "%Y-%m-%d %H:%M:%S %z", "2006-01-02 15:04:05 -0700"},
+		{"colon zone", "%Y-%m-%dT%H:%M:%S%:z", "2006-01-02T15:04:05-07:00"},
+		{"named zone", "%a, %d %b %Y %H:%M:%S %Z", "Mon, 02 Jan 2006 15:04:05 MST"},
+		{"day of year", "%Y-%j", "2006-002"},
+		{"fractional micro", "%H:%M:%S.%f", "15:04:05.000000"},
+		{"fractional nano", "%H:%M:%S.%N", "15:04:05.999999999"},
+		{"literal percent", "100%% done %Y", "100% done 2006"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := strftimeToGoLayout(tt.format)
+			if err != nil {
+				t.Fatalf("strftimeToGoLayout returned error: %v", err)
+			}
+
+			if got != tt.expected {
+				t.Fatalf("unexpected layout\nexpected: %q\n     got: %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestStrftimeToGoLayoutErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+	}{
+		{"unknown directive", "%Y-%q"},
+		{"dangling percent", "%Y-%"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := strftimeToGoLayout(tt.format); err == nil {
+				t.Fatalf("expected error for format %q, got none", tt.format)
+			}
+		})
+	}
+}
+
+func TestStringToTimeStrftime(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		format   string
+		expected time.Time
+	}{
+		{
+			name:     "iso8601",
+			input:    "2006-01-02T15:04:05",
+			format:   "%Y-%m-%dT%H:%M:%S",
+			expected: time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name:     "syslog",
+			input:    "Jan  2 15:04:05",
+			format:   "%b %e %H:%M:%S",
+			expected: time.Date(0, time.January, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name:     "epoch seconds",
+			input:    "1136239445",
+			format:   "%s",
+			expected: time.Unix(1136239445, 0).UTC(),
+		},
+		{
+			name:     "epoch seconds with fraction",
+			input:    "1136239445.5",
+			format:   "%s.%f",
+			expected: time.UnixMicro(1136239445500000).UTC(),
+		},
+		{
+			name:     "fractional seconds with literal dot",
+			input:    "15:04:05.123456",
+			format:   "%H:%M:%S.%f",
+			expected: time.Date(0, time.January, 1, 15, 4, 5, 123456000, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := stringToTime(tt.input, tt.format)
+			if err != nil {
+				t.Fatalf("stringToTime returned error: %v", err)
+			}
+
+			if !got.Equal(tt.expected) {
+				t.Fatalf("unexpected time\nexpected: %v\n     got: %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestStringToTimeAutoDetectNginxAccessLog(t *testing.T) {
+	input := "10/Oct/2000:13:55:36 -0700"
+	expected := time.Date(2000, time.October, 10, 13, 55, 36, 0, time.FixedZone("", -7*60*60))
+
+	got, err := stringToTime(input, "")
+	if err != nil {
+		t.Fatalf("stringToTime returned error: %v", err)
+	}
+
+	if !got.Equal(expected) {
+		t.Fatalf("unexpected time\nexpected: %v\n     got: %v", expected, got)
+	}
+}
+
+func TestAggregators(t *testing.T) {
+	tests := []struct {
+		name     string
+		agg      string
+		samples  []float64
+		expected float64
+	}{
+		{"count", "count", []float64{5, 5, 5}, 3},
+		{"sum", "sum", []float64{1, 2, 3.5}, 6.5},
+		{"avg", "avg", []float64{1, 2, 3}, 2},
+		{"max", "max", []float64{3, 1, 4, 1, 5}, 5},
+		{"min", "min", []float64{3, 1, 4, 1, 5}, 1},
+		{"p50", "p50", []float64{1, 2, 3, 4, 5}, 3},
+		{"p90", "p90", []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newAgg, err := newAggregatorFunc(tt.agg)
+			if err != nil {
+				t.Fatalf("newAggregatorFunc returned error: %v", err)
+			}
+
+			agg := newAgg()
+			for _, v := range tt.samples {
+				agg.add(v)
+			}
+
+			if got := agg.value(); got != tt.expected {
+				t.Fatalf("unexpected value\nexpected: %v\n     got: %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNewAggregatorFuncInvalid(t *testing.T) {
+	if _, err := newAggregatorFunc("bogus"); err == nil {
+		t.Fatal("expected error for unknown aggregation, got none")
+	}
+}
+
+// TestBinsCollapseOtherSeriesCount reproduces the maintainer-reported
+// regression: with more series than fit on the legend, the low-rank
+// series collapsed into "(Other)" must have their counts summed, not
+// the number of collapsed series.
+func TestBinsCollapseOtherSeriesCount(t *testing.T) {
+	newAgg, err := newAggregatorFunc("count")
+	if err != nil {
+		t.Fatalf("newAggregatorFunc returned error: %v", err)
+	}
+
+	b := newBins(time.Minute, newAgg)
+	at := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	counts := map[string]int{
+		"a": 10, "b": 9, "c": 8, "d": 7, "e": 6, // top 5, kept as-is
+		"x": 5, "y": 4, "z": 3, // low-rank, collapsed into "(Other)"
+	}
+	for name, n := range counts {
+		for range n {
+			b.add(at, name, 1)
+		}
+	}
+
+	seriesLimit := len(barStyles) + 1
+	seriesNames := b.collapseOtherSeries(seriesLimit, newAgg)
+
+	if !slices.Contains(seriesNames, otherSeriesName) {
+		t.Fatalf("expected %q in collapsed series names, got %v", otherSeriesName, seriesNames)
+	}
+
+	otherAgg, ok := b.values[0][otherSeriesName]
+	if !ok {
+		t.Fatalf("expected %q series in bin, got %v", otherSeriesName, b.values[0])
+	}
+
+	const wantOther = 5 + 4 + 3
+	if got := otherAgg.value(); got != wantOther {
+		t.Fatalf("unexpected %q count\nexpected: %v\n     got: %v", otherSeriesName, wantOther, got)
+	}
+}
+
+func TestValueSelectorField(t *testing.T) {
+	vs, err := parseValueSelector("field:3")
+	if err != nil {
+		t.Fatalf("parseValueSelector returned error: %v", err)
+	}
+
+	v, series, err := vs.parseValue("web 1 2 42")
+	if err != nil {
+		t.Fatalf("parseValue returned error: %v", err)
+	}
+	if v != 2 {
+		t.Fatalf("unexpected value: %v", v)
+	}
+	if series != "web 1 42" {
+		t.Fatalf("unexpected series label: %q", series)
+	}
+}
+
+// TestValueSelectorFieldGroupsAcrossValues verifies that lines differing
+// only in the selected numeric field collapse into the same series, so
+// the aggregator actually sees more than one sample per series.
+func TestValueSelectorFieldGroupsAcrossValues(t *testing.T) {
+	vs, err := parseValueSelector("field:2")
+	if err != nil {
+		t.Fatalf("parseValueSelector returned error: %v", err)
+	}
+
+	_, s1, err := vs.parseValue("svc 100")
+	if err != nil {
+		t.Fatalf("parseValue returned error: %v", err)
+	}
+	_, s2, err := vs.parseValue("svc 200")
+	if err != nil {
+		t.Fatalf("parseValue returned error: %v", err)
+	}
+	if s1 != s2 {
+		t.Fatalf("expected lines to share a series label, got %q and %q", s1, s2)
+	}
+}
+
+func TestValueSelectorRegex(t *testing.T) {
+	vs, err := parseValueSelector(`regex:latency=(\d+\.?\d*)ms`)
+	if err != nil {
+		t.Fatalf("parseValueSelector returned error: %v", err)
+	}
+
+	v, series, err := vs.parseValue("GET /api latency=123.5ms")
+	if err != nil {
+		t.Fatalf("parseValue returned error: %v", err)
+	}
+	if v != 123.5 {
+		t.Fatalf("unexpected value: %v", v)
+	}
+	if series != "GET /api" {
+		t.Fatalf("unexpected series label: %q", series)
+	}
+
+	_, series2, err := vs.parseValue("GET /api latency=456.7ms")
+	if err != nil {
+		t.Fatalf("parseValue returned error: %v", err)
+	}
+	if series2 != series {
+		t.Fatalf("expected lines to share a series label, got %q and %q", series, series2)
+	}
+}
+
+func TestValueSelectorKey(t *testing.T) {
+	tests := []struct {
+		name           string
+		line           string
+		expected       float64
+		expectedSeries string
+	}{
+		{"logfmt", `service=api duration_ms=42.5 status=200`, 42.5, "service=api status=200"},
+		{"json", `{"service":"api","duration_ms":42.5}`, 42.5, `{"service":"api"}`},
+		{"json no other fields", `{"duration_ms":42.5}`, 42.5, ""},
+	}
+
+	vs, err := parseValueSelector("key:duration_ms")
+	if err != nil {
+		t.Fatalf("parseValueSelector returned error: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, series, err := vs.parseValue(tt.line)
+			if err != nil {
+				t.Fatalf("parseValue returned error: %v", err)
+			}
+			if v != tt.expected {
+				t.Fatalf("unexpected value: %v", v)
+			}
+			if series != tt.expectedSeries {
+				t.Fatalf("unexpected series label\nexpected: %q\n     got: %q", tt.expectedSeries, series)
+			}
+		})
+	}
+}
+
+func TestParseValueSelectorInvalid(t *testing.T) {
+	tests := []string{"bogus", "field:0", "field:abc", "regex:(", "regex:nogroup", "key:"}
+
+	for _, spec := range tests {
+		t.Run(spec, func(t *testing.T) {
+			if _, err := parseValueSelector(spec); err == nil {
+				t.Fatalf("expected error for selector %q, got none", spec)
+			}
+		})
+	}
+}
+
+// fixtureHistogram returns a small, deterministic two-bin/two-series
+// histogram shared by the renderer golden tests below.
+func fixtureHistogram() *histogram {
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	return &histogram{
+		totalCount:  6,
+		minTime:     start,
+		maxTime:     start.Add(10 * time.Minute),
+		interval:    5 * time.Minute,
+		agg:         "sum",
+		seriesNames: []string{"api", "web"},
+		bins: []binRecord{
+			{
+				start:  start,
+				end:    start.Add(5 * time.Minute),
+				total:  4,
+				series: map[string]float64{"api": 3, "web": 1},
+			},
+			{
+				start:  start.Add(5 * time.Minute),
+				end:    start.Add(10 * time.Minute),
+				total:  2,
+				series: map[string]float64{"web": 2},
+			},
+		},
+	}
+}
+
+func fixtureStyleFunc(name string, count int) string {
+	chr := "#"
+	if name == "web" {
+		chr = "*"
+	}
+	return strings.Repeat(chr, count)
+}
+
+func testRenderAgainstGolden(t *testing.T, r renderer, goldenPath string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := r.render(&buf, fixtureHistogram()); err != nil {
+		t.Fatalf("render returned error: %v", err)
+	}
+
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+
+	if buf.String() != string(want) {
+		t.Fatalf("render output does not match %s\n--- got ---\n%s\n--- want ---\n%s", goldenPath, buf.String(), want)
+	}
+}
+
+func TestTextRendererGolden(t *testing.T) {
+	r := &textRenderer{barlen: 20, styleFunc: fixtureStyleFunc}
+	testRenderAgainstGolden(t, r, "testdata/render_text.golden")
+}
+
+func TestJSONRendererGolden(t *testing.T) {
+	r := &jsonRenderer{}
+	testRenderAgainstGolden(t, r, "testdata/render_json.golden")
+}
+
+func TestCSVRendererGoldenLong(t *testing.T) {
+	r := &csvRenderer{comma: ',', shape: "long"}
+	testRenderAgainstGolden(t, r, "testdata/render_csv_long.golden")
+}
+
+func TestCSVRendererGoldenWide(t *testing.T) {
+	r := &csvRenderer{comma: ',', shape: "wide"}
+	testRenderAgainstGolden(t, r, "testdata/render_csv_wide.golden")
+}
+
+func TestTSVRendererGolden(t *testing.T) {
+	r := &csvRenderer{comma: '\t', shape: "long"}
+	testRenderAgainstGolden(t, r, "testdata/render_tsv.golden")
+}
+
+// TestRenderersEmptyHistogram verifies that the json and csv renderers keep
+// emitting well-formed output when no input lines matched, rather than
+// falling back to the plain-text "Total count = 0" notice.
+func TestRenderersEmptyHistogram(t *testing.T) {
+	empty := &histogram{agg: "count"}
+
+	var buf bytes.Buffer
+	if err := (&jsonRenderer{}).render(&buf, empty); err != nil {
+		t.Fatalf("jsonRenderer.render returned error: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"bins": []`) {
+		t.Fatalf("expected empty bins array in json output, got: %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := (&csvRenderer{comma: ',', shape: "long"}).render(&buf, empty); err != nil {
+		t.Fatalf("csvRenderer.render returned error: %v", err)
+	}
+	if buf.String() != "timestamp,series,count\n" {
+		t.Fatalf("unexpected csv output: %q", buf.String())
+	}
+}